@@ -0,0 +1,141 @@
+// Package blockcache implements a small LRU cache for full Bitcoin blocks.
+// Lookups are serialized per block hash so that N concurrent callers asking
+// for the same, not-yet-cached block result in exactly one fetch from the
+// backing chain source. The design mirrors the approach taken by lnd's own
+// blockcache package.
+package blockcache
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/neutrino/cache"
+	"github.com/lightninglabs/neutrino/cache/lru"
+	"github.com/lightningnetwork/lnd/multimutex"
+)
+
+const (
+	// DefaultNumBlocks is the number of blocks the cache will hold if the
+	// caller doesn't request a specific capacity. Proof verification
+	// only ever needs to keep a handful of recently touched blocks
+	// around, so we default to a small window rather than lnd's much
+	// larger byte-based budget.
+	DefaultNumBlocks = 20
+
+	// avgMaxBlockSize is a conservative upper bound, in bytes, on the
+	// serialized size of a block. It's used to translate a block-count
+	// budget into the byte budget the underlying LRU cache actually
+	// tracks.
+	avgMaxBlockSize = 4_000_000
+)
+
+// CacheableBlock wraps a *wire.MsgBlock so it can be stored in the generic
+// LRU cache, which requires its values to report their own size.
+type CacheableBlock struct {
+	*wire.MsgBlock
+}
+
+// Size returns the serialized size of the wrapped block, in bytes. This
+// implements the cache.Value interface expected by lru.Cache.
+func (c *CacheableBlock) Size() (uint64, error) {
+	return uint64(c.MsgBlock.SerializeSize()), nil
+}
+
+// Config holds the knobs used to size a BlockCache.
+type Config struct {
+	// NumBlocks, when set, sizes the cache to hold at least this many
+	// blocks of up to avgMaxBlockSize bytes each; smaller blocks let it
+	// hold more. Ignored if MaxBytes is set.
+	NumBlocks uint64
+
+	// MaxBytes, when set, caps the cache at this many bytes of
+	// serialized block data, regardless of block count. This takes
+	// precedence over NumBlocks.
+	MaxBytes uint64
+}
+
+// capacity returns the byte capacity the cache should be created with, given
+// the config.
+func (c Config) capacity() uint64 {
+	switch {
+	case c.MaxBytes > 0:
+		return c.MaxBytes
+
+	case c.NumBlocks > 0:
+		return c.NumBlocks * avgMaxBlockSize
+
+	default:
+		return DefaultNumBlocks * avgMaxBlockSize
+	}
+}
+
+// FetchBlockFunc retrieves a block from the backing chain source on a cache
+// miss.
+type FetchBlockFunc func(hash chainhash.Hash) (*wire.MsgBlock, error)
+
+// BlockCache is an LRU cache for full blocks, keyed by block hash. A
+// per-hash mutex ensures that concurrent lookups for the same hash only
+// trigger a single call to the fetch function; every other caller simply
+// waits for the result to land in the cache.
+type BlockCache struct {
+	cache *lru.Cache[chainhash.Hash, *CacheableBlock]
+
+	hashMtx *multimutex.Mutex[chainhash.Hash]
+
+	hits, misses uint64
+}
+
+// NewBlockCache creates a new BlockCache using the given config.
+func NewBlockCache(cfg Config) *BlockCache {
+	return &BlockCache{
+		cache: lru.NewCache[chainhash.Hash, *CacheableBlock](
+			cfg.capacity(),
+		),
+		hashMtx: multimutex.NewMutex[chainhash.Hash](),
+	}
+}
+
+// GetBlock returns the block for the given hash, serving it from the cache
+// if present. On a miss, fetch is invoked to retrieve the block, which is
+// then stored in the cache before being returned. Concurrent calls for the
+// same hash are serialized, so only one call to fetch is ever in flight for
+// a given hash at a time.
+func (b *BlockCache) GetBlock(hash chainhash.Hash,
+	fetch FetchBlockFunc) (*wire.MsgBlock, error) {
+
+	b.hashMtx.Lock(hash)
+	defer b.hashMtx.Unlock(hash)
+
+	cached, err := b.cache.Get(hash)
+	switch {
+	case err == nil:
+		atomic.AddUint64(&b.hits, 1)
+		return cached.MsgBlock, nil
+
+	case errors.Is(err, cache.ErrElementNotFound):
+		// Fall through to fetch the block below.
+
+	default:
+		return nil, err
+	}
+
+	atomic.AddUint64(&b.misses, 1)
+
+	block, err := fetch(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := b.cache.Put(hash, &CacheableBlock{MsgBlock: block}); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// Stats returns the running hit and miss counters for the cache.
+func (b *BlockCache) Stats() (hits uint64, misses uint64) {
+	return atomic.LoadUint64(&b.hits), atomic.LoadUint64(&b.misses)
+}
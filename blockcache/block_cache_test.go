@@ -0,0 +1,105 @@
+package blockcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlockCacheDeduplicatesConcurrentFetches asserts that N concurrent
+// GetBlock calls for the same hash result in exactly one call to the fetch
+// function, with every caller still receiving the fetched block.
+func TestBlockCacheDeduplicatesConcurrentFetches(t *testing.T) {
+	t.Parallel()
+
+	const numCallers = 20
+
+	hash := chainhash.Hash{1, 2, 3}
+	block := &wire.MsgBlock{
+		Header: wire.BlockHeader{Nonce: 42},
+	}
+
+	var numFetches uint32
+	var ready sync.WaitGroup
+	ready.Add(numCallers)
+
+	start := make(chan struct{})
+
+	fetch := func(h chainhash.Hash) (*wire.MsgBlock, error) {
+		atomic.AddUint32(&numFetches, 1)
+
+		// Give every other goroutine a chance to pile up behind the
+		// hash mutex while this fetch is "in flight".
+		time.Sleep(50 * time.Millisecond)
+
+		return block, nil
+	}
+
+	bc := NewBlockCache(Config{NumBlocks: 10})
+
+	var wg sync.WaitGroup
+	results := make([]*wire.MsgBlock, numCallers)
+	for i := 0; i < numCallers; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			ready.Done()
+			<-start
+
+			got, err := bc.GetBlock(hash, fetch)
+			require.NoError(t, err)
+
+			results[idx] = got
+		}(i)
+	}
+
+	ready.Wait()
+	close(start)
+	wg.Wait()
+
+	require.EqualValues(t, 1, atomic.LoadUint32(&numFetches))
+
+	for _, got := range results {
+		require.Equal(t, block, got)
+	}
+
+	hits, misses := bc.Stats()
+	require.EqualValues(t, numCallers-1, hits)
+	require.EqualValues(t, 1, misses)
+}
+
+// TestBlockCacheHit asserts that a second lookup for an already-cached hash
+// doesn't invoke the fetch function again.
+func TestBlockCacheHit(t *testing.T) {
+	t.Parallel()
+
+	hash := chainhash.Hash{9, 9, 9}
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Nonce: 7}}
+
+	var numFetches int
+	fetch := func(h chainhash.Hash) (*wire.MsgBlock, error) {
+		numFetches++
+		return block, nil
+	}
+
+	bc := NewBlockCache(Config{NumBlocks: 10})
+
+	_, err := bc.GetBlock(hash, fetch)
+	require.NoError(t, err)
+
+	got, err := bc.GetBlock(hash, fetch)
+	require.NoError(t, err)
+	require.Equal(t, block, got)
+
+	require.Equal(t, 1, numFetches)
+
+	hits, misses := bc.Stats()
+	require.EqualValues(t, 1, hits)
+	require.EqualValues(t, 1, misses)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -11,6 +12,7 @@ import (
 	"github.com/lightninglabs/lndclient"
 	"github.com/lightninglabs/neutrino/cache/lru"
 	"github.com/lightninglabs/taproot-assets/asset"
+	"github.com/lightninglabs/taproot-assets/blockcache"
 	"github.com/lightninglabs/taproot-assets/proof"
 	"github.com/lightninglabs/taproot-assets/tapdb"
 	"github.com/lightninglabs/taproot-assets/tapgarden"
@@ -36,23 +38,63 @@ type LndRpcChainBridge struct {
 
 	getBlockHeaderSupported *bool
 
-	blockTimestampCache *lru.Cache[uint32, cacheableTimestamp]
+	// blockTimestampCache caches block timestamps by height, alongside
+	// the hash of the block that timestamp was observed on. Keeping the
+	// hash lets us detect and evict entries that a reorg has made stale.
+	blockTimestampCache *lru.Cache[uint32, cachedBlockTimestamp]
+
+	// cachedHeights tracks which heights are currently present in
+	// blockTimestampCache, so invalidateStaleTimestamps doesn't have to
+	// probe every height back to genesis looking for entries to check.
+	cachedHeights *cachedHeightSet
+
+	// blockCache caches recently fetched blocks, keyed by block hash, so
+	// that repeated lookups for the same block (e.g. while verifying a
+	// proof file with many transitions in a narrow height range) don't
+	// each round-trip to the chain backend.
+	blockCache *blockcache.BlockCache
 
 	assetStore *tapdb.AssetStore
+
+	reorgMu        sync.Mutex
+	reorgSubs      map[int]chan ReorgEvent
+	nextReorgSubID int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 }
 
 // NewLndRpcChainBridge creates a new chain bridge from an active lnd services
-// client.
+// client. blockCacheCfg controls the size of the in-memory block cache used
+// to dedupe GetBlock calls; the zero value results in a small, default-sized
+// cache (see blockcache.DefaultNumBlocks).
 func NewLndRpcChainBridge(lnd *lndclient.LndServices,
-	assetStore *tapdb.AssetStore) *LndRpcChainBridge {
+	assetStore *tapdb.AssetStore,
+	blockCacheCfg blockcache.Config) *LndRpcChainBridge {
 
-	return &LndRpcChainBridge{
+	bridge := &LndRpcChainBridge{
 		lnd: lnd,
-		blockTimestampCache: lru.NewCache[uint32, cacheableTimestamp](
+		blockTimestampCache: lru.NewCache[uint32, cachedBlockTimestamp](
 			maxNumBlocksInCache,
 		),
-		assetStore: assetStore,
+		cachedHeights: newCachedHeightSet(),
+		blockCache:    blockcache.NewBlockCache(blockCacheCfg),
+		assetStore:    assetStore,
+		reorgSubs:     make(map[int]chan ReorgEvent),
+		quit:          make(chan struct{}),
 	}
+
+	bridge.wg.Add(1)
+	go bridge.watchReorgs()
+
+	return bridge
+}
+
+// Stop shuts down the background reorg watcher started by
+// NewLndRpcChainBridge.
+func (l *LndRpcChainBridge) Stop() {
+	close(l.quit)
+	l.wg.Wait()
 }
 
 // RegisterConfirmationsNtfn registers an intent to be notified once
@@ -100,11 +142,20 @@ func (l *LndRpcChainBridge) RegisterBlockEpochNtfn(
 func (l *LndRpcChainBridge) GetBlock(ctx context.Context,
 	hash chainhash.Hash) (*wire.MsgBlock, error) {
 
-	block, err := l.lnd.ChainKit.GetBlock(ctx, hash)
+	block, err := l.blockCache.GetBlock(hash, func(
+		hash chainhash.Hash) (*wire.MsgBlock, error) {
+
+		return l.lnd.ChainKit.GetBlock(ctx, hash)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve block: %w", err)
 	}
 
+	if hits, misses := l.blockCache.Stats(); (hits+misses)%10 == 0 {
+		log.Debugf("Block cache stats: hits=%d, misses=%d", hits,
+			misses)
+	}
+
 	return block, nil
 }
 
@@ -220,16 +271,20 @@ func (l *LndRpcChainBridge) GetBlockTimestamp(ctx context.Context,
 		return 0
 	}
 
-	cacheTS, err := l.blockTimestampCache.Get(height)
-	if err == nil {
-		return int64(cacheTS)
-	}
-
 	hash, err := l.lnd.ChainKit.GetBlockHash(ctx, int64(height))
 	if err != nil {
 		return 0
 	}
 
+	// Only trust the cached entry if it was recorded against the same
+	// hash we'd get by looking up height right now. A reorg may have
+	// swapped in a different block at this height since we cached it, in
+	// which case we fall through and re-fetch.
+	cached, err := l.blockTimestampCache.Get(height)
+	if err == nil && cached.hash == hash {
+		return int64(cached.timestamp)
+	}
+
 	// Let's see if we can get the block header directly.
 	var header *wire.BlockHeader
 	if l.GetBlockHeaderSupported(ctx) {
@@ -247,7 +302,11 @@ func (l *LndRpcChainBridge) GetBlockTimestamp(ctx context.Context,
 	}
 
 	ts := uint32(header.Timestamp.Unix())
-	_, _ = l.blockTimestampCache.Put(height, cacheableTimestamp(ts))
+	_, _ = l.blockTimestampCache.Put(height, cachedBlockTimestamp{
+		hash:      hash,
+		timestamp: ts,
+	})
+	l.cachedHeights.add(height)
 
 	return int64(ts)
 }
@@ -269,7 +328,9 @@ func (l *LndRpcChainBridge) EstimateFee(ctx context.Context,
 }
 
 // GenFileChainLookup generates a chain lookup interface for the given
-// proof file that can be used to validate proofs.
+// proof file that can be used to validate proofs. Because the returned
+// lookup calls back into l for block data, the whole proof file verification
+// pass shares l's blockCache.
 func (l *LndRpcChainBridge) GenFileChainLookup(
 	f *proof.File) asset.ChainLookup {
 
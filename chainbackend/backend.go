@@ -0,0 +1,85 @@
+// Package chainbackend provides tapgarden.ChainBridge implementations that
+// talk directly to a btcd or bitcoind node, instead of relying on an lnd
+// node's ChainKit/ChainNotifier/WalletKit RPCs. This lets tapd (or a
+// universe server that only needs chain data) run against a shared Bitcoin
+// node without standing up a dedicated lnd instance.
+//
+// Asset minting and transfers still need a signer, so both backends accept
+// a WalletSigner shim that's backed by lnd; only block/header/fee lookups,
+// confirmation notifications and transaction broadcast are served directly
+// by btcd/bitcoind.
+package chainbackend
+
+import "fmt"
+
+// Backend identifies which chain backend tapd should use for block data,
+// confirmation notifications and transaction broadcast.
+type Backend string
+
+const (
+	// BackendLnd instructs tapd to keep using lnd's ChainKit/
+	// ChainNotifier/WalletKit RPCs, exactly as it does today.
+	BackendLnd Backend = "lnd"
+
+	// BackendBtcd instructs tapd to talk directly to a btcd node via
+	// rpcclient, using websocket notifications for confirmations and new
+	// blocks.
+	BackendBtcd Backend = "btcd"
+
+	// BackendBitcoind instructs tapd to talk directly to a bitcoind node
+	// via its JSON-RPC interface, using a ZMQ subscription for block and
+	// transaction notifications.
+	BackendBitcoind Backend = "bitcoind"
+)
+
+// String returns the string representation of the backend.
+func (b Backend) String() string {
+	return string(b)
+}
+
+// ParseBackend validates and returns the Backend for the given string.
+func ParseBackend(s string) (Backend, error) {
+	switch Backend(s) {
+	case BackendLnd, BackendBtcd, BackendBitcoind:
+		return Backend(s), nil
+
+	default:
+		return "", fmt.Errorf("unknown chain backend %q, must be one "+
+			"of: %s, %s, %s", s, BackendLnd, BackendBtcd,
+			BackendBitcoind)
+	}
+}
+
+// RPCConfig holds the connection details for a btcd or bitcoind RPC
+// endpoint.
+type RPCConfig struct {
+	// Host is the host:port of the RPC endpoint.
+	Host string
+
+	// User is the RPC username.
+	User string
+
+	// Password is the RPC password.
+	Password string
+
+	// TLSCertPath is the path to the node's TLS certificate. Only used
+	// for btcd, which speaks RPC over TLS by default.
+	TLSCertPath string
+
+	// DisableTLS disables TLS for the RPC connection. Typically only
+	// used for bitcoind, which speaks plain HTTP by default.
+	DisableTLS bool
+}
+
+// BitcoindConfig holds the bitcoind-specific connection settings, on top of
+// the common RPC settings.
+type BitcoindConfig struct {
+	RPCConfig
+
+	// ZMQBlockHost is the host:port of bitcoind's `zmqpubrawblock`
+	// publisher.
+	ZMQBlockHost string
+
+	// ZMQTxHost is the host:port of bitcoind's `zmqpubrawtx` publisher.
+	ZMQTxHost string
+}
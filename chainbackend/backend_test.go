@@ -0,0 +1,20 @@
+package chainbackend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseBackend asserts that ParseBackend accepts the three supported
+// backend names and rejects anything else.
+func TestParseBackend(t *testing.T) {
+	t.Parallel()
+
+	backend, err := ParseBackend("btcd")
+	require.NoError(t, err)
+	require.Equal(t, BackendBtcd, backend)
+
+	_, err = ParseBackend("neutrino")
+	require.Error(t, err)
+}
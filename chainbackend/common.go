@@ -0,0 +1,22 @@
+package chainbackend
+
+import (
+	"fmt"
+	"os"
+)
+
+// loadTLSCert reads the PEM-encoded TLS certificate at path, returning nil
+// if path is empty (e.g. when the RPC endpoint doesn't use TLS).
+func loadTLSCert(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	cert, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read TLS cert %v: %w", path,
+			err)
+	}
+
+	return cert, nil
+}
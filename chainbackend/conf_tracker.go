@@ -0,0 +1,166 @@
+package chainbackend
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+)
+
+// confRequest tracks a single outstanding confirmation request registered
+// via RegisterConfirmationsNtfn.
+type confRequest struct {
+	txid         *chainhash.Hash
+	pkScript     []byte
+	numConfs     uint32
+	heightHint   uint32
+	includeBlock bool
+
+	// confirmedHeight is the height of the block the txid was first seen
+	// in, or 0 if it hasn't been seen yet. It's set the first time the
+	// txid turns up while scanning a connected block, and is then used on
+	// every subsequent block to recompute the confirmation depth against
+	// the new tip. invalidateFromHeight resets it to 0 if that block is
+	// disconnected, after which the next sighting sets it again.
+	confirmedHeight    uint32
+	confirmedBlockHash chainhash.Hash
+	confirmedTxIndex   uint32
+	confirmedBlock     *wire.MsgBlock
+
+	confChan  chan *chainntnfs.TxConfirmation
+	errChan   chan error
+	reOrgChan chan struct{}
+}
+
+// confTracker matures outstanding confirmation requests as new blocks are
+// scanned. Neither btcd nor bitcoind RPC gives us a reliable, backend-
+// agnostic way to ask "how many confirmations does this tx have", so both
+// backends feed every newly connected block through processBlock instead:
+// the first time a request's txid turns up, we remember the height it was
+// mined at, and from then on every new block re-checks that height against
+// the new tip until the requested depth is reached.
+type confTracker struct {
+	mu   sync.Mutex
+	reqs map[chainhash.Hash][]*confRequest
+}
+
+// newConfTracker creates a new, empty confTracker.
+func newConfTracker() *confTracker {
+	return &confTracker{
+		reqs: make(map[chainhash.Hash][]*confRequest),
+	}
+}
+
+// register adds req to the set of outstanding confirmation requests.
+func (t *confTracker) register(req *confRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reqs[*req.txid] = append(t.reqs[*req.txid], req)
+}
+
+// processBlock records the first sighting of any outstanding request's txid
+// within block, then notifies (and removes) every request whose confirmed
+// height has reached its target depth as of height, the height of block.
+func (t *confTracker) processBlock(block *wire.MsgBlock, height uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	blockHash := block.BlockHash()
+
+	for i, tx := range block.Transactions {
+		txid := tx.TxHash()
+
+		reqs, ok := t.reqs[txid]
+		if !ok {
+			continue
+		}
+
+		for _, req := range reqs {
+			if req.confirmedHeight != 0 {
+				continue
+			}
+
+			req.confirmedHeight = height
+			req.confirmedBlockHash = blockHash
+			req.confirmedTxIndex = uint32(i)
+
+			if req.includeBlock {
+				req.confirmedBlock = block
+			}
+		}
+	}
+
+	t.matureAgainst(height)
+}
+
+// invalidateFromHeight clears the confirmed state of any outstanding
+// request that was marked confirmed at or above height. It must be called
+// whenever a block at or above height is disconnected from the main chain,
+// so a request confirmed in a block that's no longer on our best chain
+// can't spuriously mature (or, worse, underflow tipHeight-confirmedHeight
+// into a huge value) against a later, lower tip. Each invalidated request
+// is signaled on its reOrgChan, if any, the same way lndclient does for the
+// lnd-backed bridge.
+func (t *confTracker) invalidateFromHeight(height uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, reqs := range t.reqs {
+		for _, req := range reqs {
+			if req.confirmedHeight < height {
+				continue
+			}
+
+			req.confirmedHeight = 0
+			req.confirmedBlockHash = chainhash.Hash{}
+			req.confirmedTxIndex = 0
+			req.confirmedBlock = nil
+
+			if req.reOrgChan == nil {
+				continue
+			}
+
+			select {
+			case req.reOrgChan <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// matureAgainst notifies and removes every outstanding request that has
+// already been sighted and whose confirmation depth, measured against
+// tipHeight, has reached its target.
+func (t *confTracker) matureAgainst(tipHeight uint32) {
+	for txid, reqs := range t.reqs {
+		remaining := reqs[:0]
+		for _, req := range reqs {
+			if req.confirmedHeight == 0 {
+				remaining = append(remaining, req)
+				continue
+			}
+
+			confs := tipHeight - req.confirmedHeight + 1
+			if confs < req.numConfs {
+				remaining = append(remaining, req)
+				continue
+			}
+
+			confirmedHash := req.confirmedBlockHash
+			req.confChan <- &chainntnfs.TxConfirmation{
+				BlockHash:   &confirmedHash,
+				BlockHeight: req.confirmedHeight,
+				TxIndex:     req.confirmedTxIndex,
+				Block:       req.confirmedBlock,
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(t.reqs, txid)
+		} else {
+			t.reqs[txid] = remaining
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package chainbackend
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/stretchr/testify/require"
+)
+
+// txWithOutput builds a distinct transaction paying to an output whose
+// value is used purely to make the resulting txid unique across calls.
+func txWithOutput(value int64) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(value, []byte{0x51}))
+	return tx
+}
+
+func blockWithTxs(txs ...*wire.MsgTx) *wire.MsgBlock {
+	return &wire.MsgBlock{Transactions: txs}
+}
+
+// TestConfTrackerMaturesAfterEnoughConfs asserts that a request only fires
+// once the block it was first seen in has reached its requested depth, not
+// before, and that it's removed from the tracker afterwards.
+func TestConfTrackerMaturesAfterEnoughConfs(t *testing.T) {
+	t.Parallel()
+
+	tracker := newConfTracker()
+	tx := txWithOutput(1)
+	txid := tx.TxHash()
+
+	req := &confRequest{
+		txid:     &txid,
+		numConfs: 3,
+		confChan: make(chan *chainntnfs.TxConfirmation, 1),
+		errChan:  make(chan error, 1),
+	}
+	tracker.register(req)
+
+	// Seen at height 10; needs height 12 for 3 confirmations.
+	tracker.processBlock(blockWithTxs(tx), 10)
+	select {
+	case <-req.confChan:
+		t.Fatal("request matured too early")
+	default:
+	}
+
+	tracker.processBlock(blockWithTxs(txWithOutput(2)), 11)
+	select {
+	case <-req.confChan:
+		t.Fatal("request matured too early")
+	default:
+	}
+
+	tracker.processBlock(blockWithTxs(txWithOutput(3)), 12)
+	select {
+	case conf := <-req.confChan:
+		require.EqualValues(t, 10, conf.BlockHeight)
+	default:
+		t.Fatal("request never matured")
+	}
+
+	require.Empty(t, tracker.reqs)
+}
+
+// TestConfTrackerInvalidateFromHeightPreventsFalseConfirmation simulates the
+// exact reorg failure mode a confirmation tracker must not have: a request
+// seen in a block that later gets reorged out must not mature using stale
+// confirmedHeight/confirmedBlockHash once the chain reconnects at a lower
+// height. Without invalidateFromHeight, tipHeight-confirmedHeight+1
+// underflows as a uint32 and fires a false confirmation instead.
+func TestConfTrackerInvalidateFromHeightPreventsFalseConfirmation(t *testing.T) {
+	t.Parallel()
+
+	tracker := newConfTracker()
+	tx := txWithOutput(1)
+	txid := tx.TxHash()
+
+	req := &confRequest{
+		txid:     &txid,
+		numConfs: 6,
+		confChan: make(chan *chainntnfs.TxConfirmation, 1),
+		errChan:  make(chan error, 1),
+	}
+	tracker.register(req)
+
+	// tx is first seen at height 20, nowhere near matured yet.
+	tracker.processBlock(blockWithTxs(tx), 20)
+	require.EqualValues(t, 20, req.confirmedHeight)
+
+	// The chain reorgs back to height 15: blocks 16-20 are disconnected,
+	// including the one req was confirmed in.
+	tracker.invalidateFromHeight(16)
+	require.Zero(t, req.confirmedHeight)
+	require.Equal(t, chainhash.Hash{}, req.confirmedBlockHash)
+
+	// The replacement chain reconnects at height 16 without the tx (it
+	// ended up in a later block on the new chain, or not at all yet).
+	tracker.processBlock(blockWithTxs(txWithOutput(2)), 16)
+
+	// Critically, this must not have fired: had confirmedHeight still
+	// been 20, matureAgainst(16) would compute 16-20+1 which underflows
+	// to a huge uint32 value, satisfying numConfs and firing a false
+	// confirmation against the reorged-out block.
+	select {
+	case <-req.confChan:
+		t.Fatal("request fired a false confirmation after reorg")
+	default:
+	}
+}
+
+// TestConfTrackerInvalidateFromHeightSignalsReorgChan asserts that an
+// invalidated request's reOrgChan, if set, is signaled.
+func TestConfTrackerInvalidateFromHeightSignalsReorgChan(t *testing.T) {
+	t.Parallel()
+
+	tracker := newConfTracker()
+	tx := txWithOutput(1)
+	txid := tx.TxHash()
+
+	reOrgChan := make(chan struct{}, 1)
+	req := &confRequest{
+		txid:      &txid,
+		numConfs:  6,
+		confChan:  make(chan *chainntnfs.TxConfirmation, 1),
+		errChan:   make(chan error, 1),
+		reOrgChan: reOrgChan,
+	}
+	tracker.register(req)
+
+	tracker.processBlock(blockWithTxs(tx), 20)
+	tracker.invalidateFromHeight(16)
+
+	select {
+	case <-reOrgChan:
+	default:
+		t.Fatal("reOrgChan was never signaled")
+	}
+}
+
+// TestConfTrackerInvalidateFromHeightLeavesLowerConfirmationsAlone asserts
+// that invalidateFromHeight only resets requests confirmed at or above the
+// given height, leaving requests confirmed on blocks still on the best
+// chain untouched.
+func TestConfTrackerInvalidateFromHeightLeavesLowerConfirmationsAlone(t *testing.T) {
+	t.Parallel()
+
+	tracker := newConfTracker()
+
+	staleTx := txWithOutput(1)
+	staleTxid := staleTx.TxHash()
+	staleReq := &confRequest{
+		txid:     &staleTxid,
+		numConfs: 6,
+		confChan: make(chan *chainntnfs.TxConfirmation, 1),
+		errChan:  make(chan error, 1),
+	}
+	tracker.register(staleReq)
+
+	safeTx := txWithOutput(2)
+	safeTxid := safeTx.TxHash()
+	// safeReq needs more confirmations than the blocks below provide, so
+	// it's still outstanding (not matured and removed) when
+	// invalidateFromHeight runs.
+	safeReq := &confRequest{
+		txid:     &safeTxid,
+		numConfs: 100,
+		confChan: make(chan *chainntnfs.TxConfirmation, 1),
+		errChan:  make(chan error, 1),
+	}
+	tracker.register(safeReq)
+
+	tracker.processBlock(blockWithTxs(safeTx), 10)
+	tracker.processBlock(blockWithTxs(staleTx), 20)
+
+	tracker.invalidateFromHeight(16)
+
+	require.Zero(t, staleReq.confirmedHeight)
+	require.EqualValues(t, 10, safeReq.confirmedHeight)
+}
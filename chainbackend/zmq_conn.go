@@ -0,0 +1,30 @@
+package chainbackend
+
+import (
+	"time"
+
+	"github.com/lightninglabs/gozmq"
+)
+
+// zmqConn is a thin wrapper around gozmq.Conn so the rest of the package
+// doesn't need to reach for the gozmq import directly.
+type zmqConn struct {
+	*gozmq.Conn
+}
+
+// dialZMQ subscribes to every topic published on addr, timing out an
+// individual read after readDeadline so callers can periodically check for
+// shutdown.
+func dialZMQ(addr string, readDeadline time.Duration) (*zmqConn, error) {
+	conn, err := gozmq.Dial(addr, readDeadline)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Subscribe(""); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &zmqConn{Conn: conn}, nil
+}
@@ -0,0 +1,173 @@
+package taprootassets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/gcs"
+	"github.com/btcsuite/btcd/btcutil/gcs/builder"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Confirmation describes a pkScript's confirming transaction, as found by a
+// ScanHistorical pass.
+type Confirmation struct {
+	// PkScript is the output script that was being searched for.
+	PkScript []byte
+
+	// Tx is the transaction whose outputs contain a match for PkScript.
+	Tx *wire.MsgTx
+
+	// BlockHash is the hash of the block Tx was confirmed in.
+	BlockHash chainhash.Hash
+
+	// BlockHeight is the height of BlockHash.
+	BlockHeight uint32
+
+	// TxIndex is the index of Tx within its block.
+	TxIndex uint32
+}
+
+// ScanHistorical walks the main chain from startHeight to endHeight
+// (inclusive), using BIP158 compact filters to cheaply test each block for a
+// match against any of pkScripts before paying for a full block download.
+// This lets us re-detect the anchor confirmation of a proof that was
+// imported while tapd was offline (or before tapd knew about the asset),
+// without downloading every block in the scanned range.
+//
+// Every match is double-checked against the block currently at that height
+// on our best chain before being emitted, so that a match on a since
+// reorged-out block is silently dropped rather than surfaced as a false
+// confirmation.
+func (l *LndRpcChainBridge) ScanHistorical(ctx context.Context,
+	pkScripts [][]byte, startHeight,
+	endHeight uint32) (<-chan Confirmation, <-chan error) {
+
+	confChan := make(chan Confirmation)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(confChan)
+
+		for height := startHeight; height <= endHeight; height++ {
+			select {
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			default:
+			}
+
+			confs, err := l.scanHeight(ctx, height, pkScripts)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			for _, conf := range confs {
+				select {
+				case confChan <- conf:
+				case <-ctx.Done():
+					errChan <- ctx.Err()
+					return
+				}
+			}
+		}
+	}()
+
+	return confChan, errChan
+}
+
+// scanHeight tests the block at height against pkScripts using its BIP158
+// compact filter, only downloading the full block (and re-confirming it
+// against our current best chain) on a filter hit.
+func (l *LndRpcChainBridge) scanHeight(ctx context.Context, height uint32,
+	pkScripts [][]byte) ([]Confirmation, error) {
+
+	hash, err := l.GetBlockHash(ctx, int64(height))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch block hash at "+
+			"height %d: %w", height, err)
+	}
+
+	rawFilter, err := l.lnd.ChainKit.GetCFilter(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch compact filter for "+
+			"block %v: %w", hash, err)
+	}
+
+	match, err := matchCompactFilter(rawFilter, hash, pkScripts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to match compact filter for "+
+			"block %v: %w", hash, err)
+	}
+	if !match {
+		return nil, nil
+	}
+
+	block, err := l.GetBlock(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch block %v: %w", hash,
+			err)
+	}
+
+	// The filter matched, but it's possible this block has since been
+	// reorged out. Only emit confirmations if it's still the block at
+	// this height on our best chain.
+	tipHash, err := l.GetBlockHash(ctx, int64(height))
+	if err != nil {
+		return nil, fmt.Errorf("unable to re-check block hash at "+
+			"height %d: %w", height, err)
+	}
+	if tipHash != hash {
+		return nil, nil
+	}
+
+	return matchingConfirmations(block, hash, height, pkScripts), nil
+}
+
+// matchCompactFilter decodes rawFilter as a BIP158 basic filter for
+// blockHash and tests it against every script in pkScripts.
+func matchCompactFilter(rawFilter []byte, blockHash chainhash.Hash,
+	pkScripts [][]byte) (bool, error) {
+
+	filter, err := gcs.FromNBytes(
+		builder.DefaultP, builder.DefaultM, rawFilter,
+	)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse compact filter: %w",
+			err)
+	}
+
+	key := builder.DeriveKey(&blockHash)
+
+	return filter.MatchAny(key, pkScripts)
+}
+
+// matchingConfirmations returns a Confirmation for every (tx, pkScript) pair
+// in block where one of the tx's outputs pays to pkScript.
+func matchingConfirmations(block *wire.MsgBlock, blockHash chainhash.Hash,
+	height uint32, pkScripts [][]byte) []Confirmation {
+
+	var confs []Confirmation
+	for txIndex, tx := range block.Transactions {
+		for _, out := range tx.TxOut {
+			for _, pkScript := range pkScripts {
+				if !bytes.Equal(out.PkScript, pkScript) {
+					continue
+				}
+
+				confs = append(confs, Confirmation{
+					PkScript:    pkScript,
+					Tx:          tx,
+					BlockHash:   blockHash,
+					BlockHeight: height,
+					TxIndex:     uint32(txIndex),
+				})
+			}
+		}
+	}
+
+	return confs
+}
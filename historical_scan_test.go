@@ -0,0 +1,91 @@
+package taprootassets
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/gcs/builder"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchCompactFilter builds a real BIP158 basic filter for a synthetic
+// block and asserts that matchCompactFilter reports a match for a pkScript
+// that's actually an output of the block, and no match for one that isn't.
+func TestMatchCompactFilter(t *testing.T) {
+	t.Parallel()
+
+	pkScript := []byte{0x51, 0x20, 0x01, 0x02, 0x03}
+	otherScript := []byte{0x51, 0x20, 0x09, 0x09, 0x09}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(1000, pkScript))
+
+	block := &wire.MsgBlock{Transactions: []*wire.MsgTx{tx}}
+	blockHash := block.BlockHash()
+
+	filter, err := builder.BuildBasicFilter(block, nil)
+	require.NoError(t, err)
+
+	rawFilter, err := filter.NBytes()
+	require.NoError(t, err)
+
+	match, err := matchCompactFilter(
+		rawFilter, blockHash, [][]byte{otherScript, pkScript},
+	)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = matchCompactFilter(
+		rawFilter, blockHash, [][]byte{otherScript},
+	)
+	require.NoError(t, err)
+	require.False(t, match)
+}
+
+// TestMatchingConfirmations asserts that matchingConfirmations finds every
+// (tx, pkScript) pair across a block's transactions, and ignores scripts
+// that don't appear in any output.
+func TestMatchingConfirmations(t *testing.T) {
+	t.Parallel()
+
+	pkScriptA := []byte{0x51, 0x20, 0x01}
+	pkScriptB := []byte{0x51, 0x20, 0x02}
+	pkScriptC := []byte{0x51, 0x20, 0x03}
+
+	txA := wire.NewMsgTx(wire.TxVersion)
+	txA.AddTxOut(wire.NewTxOut(1000, pkScriptA))
+
+	txB := wire.NewMsgTx(wire.TxVersion)
+	txB.AddTxOut(wire.NewTxOut(2000, pkScriptB))
+	txB.AddTxOut(wire.NewTxOut(3000, pkScriptC))
+
+	block := &wire.MsgBlock{Transactions: []*wire.MsgTx{txA, txB}}
+	blockHash := block.BlockHash()
+
+	confs := matchingConfirmations(
+		block, blockHash, 123, [][]byte{pkScriptA, pkScriptC},
+	)
+	require.Len(t, confs, 2)
+
+	require.Equal(t, Confirmation{
+		PkScript:    pkScriptA,
+		Tx:          txA,
+		BlockHash:   blockHash,
+		BlockHeight: 123,
+		TxIndex:     0,
+	}, confs[0])
+
+	require.Equal(t, Confirmation{
+		PkScript:    pkScriptC,
+		Tx:          txB,
+		BlockHash:   blockHash,
+		BlockHeight: 123,
+		TxIndex:     1,
+	}, confs[1])
+
+	// A pkScript that appears in no output shouldn't produce a match.
+	none := matchingConfirmations(
+		block, blockHash, 123, [][]byte{{0x51, 0x20, 0xff}},
+	)
+	require.Empty(t, none)
+}
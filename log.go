@@ -0,0 +1,22 @@
+package taprootassets
+
+import (
+	"github.com/btcsuite/btclog"
+	"github.com/lightningnetwork/lnd/build"
+)
+
+// Subsystem defines the logging code for this subsystem.
+const Subsystem = "TAPD"
+
+// log is the default package-wide logger for this package.
+var log btclog.Logger
+
+// init initializes the package-wide logger.
+func init() {
+	UseLogger(build.NewSubLogger(Subsystem, nil))
+}
+
+// UseLogger uses a specified Logger to output package logging info.
+func UseLogger(logger btclog.Logger) {
+	log = logger
+}
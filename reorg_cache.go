@@ -0,0 +1,222 @@
+package taprootassets
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightninglabs/neutrino/cache/lru"
+)
+
+// cachedBlockTimestamp is the value type stored in
+// LndRpcChainBridge.blockTimestampCache. Storing the hash a timestamp was
+// observed on, alongside the timestamp itself, lets us detect when a reorg
+// has made a cached entry stale.
+type cachedBlockTimestamp struct {
+	hash      chainhash.Hash
+	timestamp uint32
+}
+
+// Size returns the cache weight of a single entry. We simply count
+// entries, since the stored values are fixed size and small.
+func (cachedBlockTimestamp) Size() (uint64, error) {
+	return 1, nil
+}
+
+// ReorgEvent describes a detected reorg: height is the chain tip at the time
+// the reorg was noticed, and depth is how many of our cached heights, at
+// minimum, turned out to be stale (i.e. how far back we had to walk before
+// finding a height whose cached hash still matched the canonical chain).
+type ReorgEvent struct {
+	Height uint32
+	Depth  uint32
+}
+
+// SubscribeReorgs returns a channel on which every detected reorg is
+// delivered, along with a function to cancel the subscription. This lets
+// the garden/custodian refresh any confirmation state they've cached
+// independently of the chain bridge.
+func (l *LndRpcChainBridge) SubscribeReorgs() (<-chan ReorgEvent, func()) {
+	l.reorgMu.Lock()
+	defer l.reorgMu.Unlock()
+
+	id := l.nextReorgSubID
+	l.nextReorgSubID++
+
+	sub := make(chan ReorgEvent, 1)
+	l.reorgSubs[id] = sub
+
+	cancel := func() {
+		l.reorgMu.Lock()
+		defer l.reorgMu.Unlock()
+
+		delete(l.reorgSubs, id)
+	}
+
+	return sub, cancel
+}
+
+// notifyReorg fans ev out to every active SubscribeReorgs subscriber.
+func (l *LndRpcChainBridge) notifyReorg(ev ReorgEvent) {
+	l.reorgMu.Lock()
+	defer l.reorgMu.Unlock()
+
+	for _, sub := range l.reorgSubs {
+		select {
+		case sub <- ev:
+		default:
+		}
+	}
+}
+
+// watchReorgs subscribes to new block epochs for the lifetime of the bridge
+// and, on every new block, walks our cached timestamps backwards from the
+// tip, evicting any entry whose cached hash no longer matches the canonical
+// hash at that height. This runs once per LndRpcChainBridge, started from
+// NewLndRpcChainBridge.
+func (l *LndRpcChainBridge) watchReorgs() {
+	defer l.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	epochChan, epochErrChan, err := l.RegisterBlockEpochNtfn(ctx)
+	if err != nil {
+		log.Errorf("unable to subscribe to block epochs for reorg "+
+			"detection: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case height, ok := <-epochChan:
+			if !ok {
+				return
+			}
+
+			l.invalidateStaleTimestamps(ctx, uint32(height))
+
+		case err, ok := <-epochErrChan:
+			if ok && err != nil {
+				log.Errorf("block epoch subscription error: "+
+					"%v", err)
+			}
+
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// invalidateStaleTimestamps walks cached heights backwards from tipHeight,
+// evicting any entry whose cached hash doesn't match the canonical hash at
+// that height, and notifies reorg subscribers if anything was evicted.
+func (l *LndRpcChainBridge) invalidateStaleTimestamps(ctx context.Context,
+	tipHeight uint32) {
+
+	getHash := func(height uint32) (chainhash.Hash, error) {
+		return l.GetBlockHash(ctx, int64(height))
+	}
+
+	depth := evictStaleTimestamps(
+		l.blockTimestampCache, l.cachedHeights, tipHeight, getHash,
+	)
+	if depth > 0 {
+		l.notifyReorg(ReorgEvent{Height: tipHeight, Depth: depth})
+	}
+}
+
+// evictStaleTimestamps walks the heights actually present in cache, as
+// tracked by heights, backwards from tipHeight, deleting any entry whose
+// stored hash doesn't match getHash(height), stopping as soon as it finds a
+// height where they agree (that height, and everything below it, must still
+// be on the canonical chain). It returns the number of entries evicted, i.e.
+// the reorg depth as observed through the cache.
+//
+// Entries populated by GetBlockTimestamp come from whatever heights a proof
+// happened to reference, not a contiguous window, so walking every height
+// down to genesis (as a naive implementation would) could mean hundreds of
+// wasted getHash RPCs per block for a cache that holds only a handful of
+// entries; restricting the walk to heights tracks avoids that.
+func evictStaleTimestamps(cache *lru.Cache[uint32, cachedBlockTimestamp],
+	heights *cachedHeightSet, tipHeight uint32,
+	getHash func(height uint32) (chainhash.Hash, error)) uint32 {
+
+	var depth uint32
+
+	for _, height := range heights.sortedDesc(tipHeight) {
+		cached, cacheErr := cache.Get(height)
+		if cacheErr != nil {
+			// The entry aged out of the LRU on its own; stop
+			// tracking it so future walks don't consider it.
+			heights.remove(height)
+			continue
+		}
+
+		canonicalHash, err := getHash(height)
+		if err != nil {
+			return depth
+		}
+
+		if cached.hash == canonicalHash {
+			// Found agreement; anything below this point must
+			// still be on the canonical chain.
+			return depth
+		}
+
+		cache.Delete(height)
+		heights.remove(height)
+		depth++
+	}
+
+	return depth
+}
+
+// cachedHeightSet tracks the set of heights currently present in a
+// blockTimestampCache, so evictStaleTimestamps only has to call getHash for
+// heights that are actually cached instead of every height back to genesis.
+type cachedHeightSet struct {
+	mu      sync.Mutex
+	heights map[uint32]struct{}
+}
+
+// newCachedHeightSet creates a new, empty cachedHeightSet.
+func newCachedHeightSet() *cachedHeightSet {
+	return &cachedHeightSet{heights: make(map[uint32]struct{})}
+}
+
+// add records height as present in the cache.
+func (s *cachedHeightSet) add(height uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.heights[height] = struct{}{}
+}
+
+// remove records height as no longer present in the cache.
+func (s *cachedHeightSet) remove(height uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.heights, height)
+}
+
+// sortedDesc returns every tracked height at or below tipHeight, in
+// descending order.
+func (s *cachedHeightSet) sortedDesc(tipHeight uint32) []uint32 {
+	s.mu.Lock()
+	heights := make([]uint32, 0, len(s.heights))
+	for height := range s.heights {
+		if height <= tipHeight {
+			heights = append(heights, height)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] > heights[j]
+	})
+
+	return heights
+}
@@ -0,0 +1,138 @@
+package taprootassets
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightninglabs/neutrino/cache/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func hashFor(b byte) chainhash.Hash {
+	var h chainhash.Hash
+	h[0] = b
+	return h
+}
+
+// TestEvictStaleTimestampsOn3BlockReorg simulates a 3-block reorg: heights
+// 8, 9 and 10 are replaced with new blocks, while everything at or below
+// height 7 is untouched. It asserts that only the reorged heights are
+// evicted from the cache.
+func TestEvictStaleTimestampsOn3BlockReorg(t *testing.T) {
+	t.Parallel()
+
+	cache := lru.NewCache[uint32, cachedBlockTimestamp](100)
+	heights := newCachedHeightSet()
+
+	// Populate the cache for heights 5 through 10 with their "original"
+	// hashes.
+	for height := uint32(5); height <= 10; height++ {
+		_, err := cache.Put(height, cachedBlockTimestamp{
+			hash:      hashFor(byte(height)),
+			timestamp: 1_700_000_000 + height,
+		})
+		require.NoError(t, err)
+		heights.add(height)
+	}
+
+	// The canonical chain now disagrees with our cache starting at
+	// height 8: a 3-block reorg replaced 8, 9 and 10.
+	canonical := map[uint32]chainhash.Hash{
+		5:  hashFor(5),
+		6:  hashFor(6),
+		7:  hashFor(7),
+		8:  hashFor(108),
+		9:  hashFor(109),
+		10: hashFor(110),
+	}
+	getHash := func(height uint32) (chainhash.Hash, error) {
+		return canonical[height], nil
+	}
+
+	depth := evictStaleTimestamps(cache, heights, 10, getHash)
+	require.EqualValues(t, 3, depth)
+
+	// Heights 8, 9 and 10 should have been evicted from the cache.
+	for height := uint32(8); height <= 10; height++ {
+		_, err := cache.Get(height)
+		require.Error(t, err)
+	}
+
+	// Heights 5 through 7 should be untouched.
+	for height := uint32(5); height <= 7; height++ {
+		cached, err := cache.Get(height)
+		require.NoError(t, err)
+		require.Equal(t, hashFor(byte(height)), cached.hash)
+	}
+}
+
+// TestEvictStaleTimestampsNoReorg asserts that a cache fully in agreement
+// with the canonical chain is left untouched.
+func TestEvictStaleTimestampsNoReorg(t *testing.T) {
+	t.Parallel()
+
+	cache := lru.NewCache[uint32, cachedBlockTimestamp](100)
+	heights := newCachedHeightSet()
+
+	for height := uint32(1); height <= 5; height++ {
+		_, err := cache.Put(height, cachedBlockTimestamp{
+			hash:      hashFor(byte(height)),
+			timestamp: height,
+		})
+		require.NoError(t, err)
+		heights.add(height)
+	}
+
+	getHash := func(height uint32) (chainhash.Hash, error) {
+		return hashFor(byte(height)), nil
+	}
+
+	depth := evictStaleTimestamps(cache, heights, 5, getHash)
+	require.Zero(t, depth)
+
+	for height := uint32(1); height <= 5; height++ {
+		_, err := cache.Get(height)
+		require.NoError(t, err)
+	}
+}
+
+// TestEvictStaleTimestampsSparseCache populates the cache at a handful of
+// widely-spaced heights, the way proof verification actually fills it, and
+// asserts that getHash is only ever called for those cached heights rather
+// than every height down to genesis.
+func TestEvictStaleTimestampsSparseCache(t *testing.T) {
+	t.Parallel()
+
+	cache := lru.NewCache[uint32, cachedBlockTimestamp](100)
+	heights := newCachedHeightSet()
+
+	// Only heights 10, 500 and 10_000 are cached, each against a hash
+	// that's since gone stale (a reorg deeper than all three).
+	sparseHeights := []uint32{10, 500, 10_000}
+	for _, height := range sparseHeights {
+		_, err := cache.Put(height, cachedBlockTimestamp{
+			hash:      hashFor(byte(height)),
+			timestamp: height,
+		})
+		require.NoError(t, err)
+		heights.add(height)
+	}
+
+	var queried []uint32
+	getHash := func(height uint32) (chainhash.Hash, error) {
+		queried = append(queried, height)
+		return hashFor(byte(height + 1)), nil
+	}
+
+	depth := evictStaleTimestamps(cache, heights, 10_000, getHash)
+	require.EqualValues(t, 3, depth)
+
+	// getHash must have been called exactly once per cached height, not
+	// once per height between 10_000 and genesis.
+	require.ElementsMatch(t, []uint32{10_000, 500, 10}, queried)
+
+	for _, height := range sparseHeights {
+		_, err := cache.Get(height)
+		require.Error(t, err)
+	}
+}
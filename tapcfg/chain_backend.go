@@ -0,0 +1,104 @@
+package tapcfg
+
+import (
+	"fmt"
+
+	"github.com/lightninglabs/lndclient"
+	taprootassets "github.com/lightninglabs/taproot-assets"
+	"github.com/lightninglabs/taproot-assets/blockcache"
+	"github.com/lightninglabs/taproot-assets/chainbackend"
+	"github.com/lightninglabs/taproot-assets/tapdb"
+	"github.com/lightninglabs/taproot-assets/tapgarden"
+)
+
+// ChainBackendConfig holds the `--chain.*` settings that select and
+// configure tapd's ChainBridge backend.
+type ChainBackendConfig struct {
+	// Backend selects which chain backend to use: "lnd" (the default),
+	// "btcd" or "bitcoind".
+	Backend string `long:"backend" description:"the chain backend to use for block/header lookups, confirmation notifications and tx broadcast: lnd, btcd, or bitcoind" choice:"lnd" choice:"btcd" choice:"bitcoind"`
+
+	// BlockCacheNumBlocks is the number of blocks to keep in the shared
+	// block cache, regardless of which backend is selected.
+	BlockCacheNumBlocks uint64 `long:"blockcachenumblocks" description:"the number of blocks to keep in the in-memory block cache"`
+
+	Btcd     BtcdConfig     `group:"btcd" namespace:"btcd" description:"btcd settings, used when backend=btcd"`
+	Bitcoind BitcoindConfig `group:"bitcoind" namespace:"bitcoind" description:"bitcoind settings, used when backend=bitcoind"`
+}
+
+// BtcdConfig holds the `--chain.btcd.*` RPC connection settings.
+type BtcdConfig struct {
+	RPCHost    string `long:"rpchost" description:"the host:port of the btcd RPC server"`
+	RPCUser    string `long:"rpcuser" description:"username for btcd RPC authentication"`
+	RPCPass    string `long:"rpcpass" description:"password for btcd RPC authentication"`
+	RawRPCCert string `long:"rawrpccert" description:"path to btcd's TLS certificate"`
+	NoTLS      bool   `long:"notls" description:"disable TLS for the btcd RPC connection"`
+}
+
+// BitcoindConfig holds the `--chain.bitcoind.*` RPC and ZMQ connection
+// settings.
+type BitcoindConfig struct {
+	RPCHost        string `long:"rpchost" description:"the host:port of the bitcoind RPC server"`
+	RPCUser        string `long:"rpcuser" description:"username for bitcoind RPC authentication"`
+	RPCPass        string `long:"rpcpass" description:"password for bitcoind RPC authentication"`
+	ZMQPubRawBlock string `long:"zmqpubrawblock" description:"the host:port of bitcoind's zmqpubrawblock publisher"`
+	ZMQPubRawTx    string `long:"zmqpubrawtx" description:"the host:port of bitcoind's zmqpubrawtx publisher"`
+}
+
+// DefaultChainBackendConfig returns a ChainBackendConfig with the same
+// defaults tapd has always used: the lnd-backed ChainBridge, with the
+// default block cache size.
+func DefaultChainBackendConfig() *ChainBackendConfig {
+	return &ChainBackendConfig{
+		Backend:             chainbackend.BackendLnd.String(),
+		BlockCacheNumBlocks: blockcache.DefaultNumBlocks,
+	}
+}
+
+// NewChainBridge constructs the tapgarden.ChainBridge implementation
+// selected by cfg. Regardless of the backend chosen for chain data, lnd is
+// always available and is what minting/transfer PSBTs are signed with; only
+// block/header lookups, confirmation notifications, fee estimation and
+// broadcast are affected by cfg.Backend.
+func NewChainBridge(cfg *ChainBackendConfig, lnd *lndclient.LndServices,
+	assetStore *tapdb.AssetStore) (tapgarden.ChainBridge, error) {
+
+	backend, err := chainbackend.ParseBackend(cfg.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	blockCacheCfg := blockcache.Config{NumBlocks: cfg.BlockCacheNumBlocks}
+
+	switch backend {
+	case chainbackend.BackendLnd:
+		return taprootassets.NewLndRpcChainBridge(
+			lnd, assetStore, blockCacheCfg,
+		), nil
+
+	case chainbackend.BackendBtcd:
+		return chainbackend.NewBtcdChainBridge(chainbackend.RPCConfig{
+			Host:        cfg.Btcd.RPCHost,
+			User:        cfg.Btcd.RPCUser,
+			Password:    cfg.Btcd.RPCPass,
+			TLSCertPath: cfg.Btcd.RawRPCCert,
+			DisableTLS:  cfg.Btcd.NoTLS,
+		}, blockCacheCfg, assetStore)
+
+	case chainbackend.BackendBitcoind:
+		return chainbackend.NewBitcoindChainBridge(
+			chainbackend.BitcoindConfig{
+				RPCConfig: chainbackend.RPCConfig{
+					Host:     cfg.Bitcoind.RPCHost,
+					User:     cfg.Bitcoind.RPCUser,
+					Password: cfg.Bitcoind.RPCPass,
+				},
+				ZMQBlockHost: cfg.Bitcoind.ZMQPubRawBlock,
+				ZMQTxHost:    cfg.Bitcoind.ZMQPubRawTx,
+			}, blockCacheCfg, assetStore,
+		)
+
+	default:
+		return nil, fmt.Errorf("unhandled chain backend: %v", backend)
+	}
+}